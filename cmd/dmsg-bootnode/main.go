@@ -0,0 +1,71 @@
+// Command dmsg-bootnode runs a standalone discovery bootnode: a well-known
+// rendezvous point that lets dmsg clients and servers find each other via
+// the discovery package's UDP protocol instead of a hard-coded server list.
+package main
+
+import (
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SkycoinProject/dmsg/cipher"
+	"github.com/SkycoinProject/dmsg/discovery"
+)
+
+func main() {
+	var (
+		addr   = flag.String("addr", ":9090", "UDP address to listen on")
+		seed   = flag.String("sk", "", "hex-encoded secret key; a random one is generated if empty")
+		gcEach = flag.Duration("gc-interval", 5*time.Minute, "how often to prune stale node entries")
+	)
+	flag.Parse()
+
+	log := logrus.New()
+
+	var (
+		pk cipher.PubKey
+		sk cipher.SecKey
+	)
+	if *seed == "" {
+		pk, sk = cipher.GenerateKeyPair()
+		log.WithField("pk", pk).Info("Generated new bootnode identity.")
+	} else {
+		if err := sk.Set(*seed); err != nil {
+			log.WithError(err).Fatal("Invalid -sk value.")
+		}
+		var err error
+		pk, err = sk.PubKey()
+		if err != nil {
+			log.WithError(err).Fatal("Failed to derive public key from -sk.")
+		}
+	}
+
+	bootnode := discovery.NewBootnode(log, pk, sk)
+
+	go func() {
+		ticker := time.NewTicker(*gcEach)
+		defer ticker.Stop()
+		for range ticker.C {
+			if n := bootnode.GC(); n > 0 {
+				log.WithField("removed", n).Debug("Pruned stale nodes.")
+			}
+		}
+	}()
+
+	go func() {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+		<-sigCh
+		log.Info("Shutting down dmsg-bootnode.")
+		_ = bootnode.Close() //nolint:errcheck
+	}()
+
+	log.WithField("addr", *addr).WithField("pk", pk).Info("dmsg-bootnode listening.")
+	if err := bootnode.ListenAndServe(*addr); err != nil {
+		log.WithError(err).Fatal("dmsg-bootnode stopped.")
+	}
+}
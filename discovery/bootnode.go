@@ -0,0 +1,137 @@
+package discovery
+
+import (
+	"net"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+// maxPacketSize is the size of the buffer a single UDP datagram is read
+// into. FindServers replies carry at most 32 nodes; their encoded size is
+// not checked against maxPacketSize.
+const maxPacketSize = 8192
+
+// Bootnode answers Ping/FindServers requests from dmsg clients and servers,
+// maintaining an in-memory, ageing table of known dmsg servers. It never
+// dials out and requires no relaying: it is reachable over plain UDP,
+// typically behind a static, well-known address.
+type Bootnode struct {
+	pk  cipher.PubKey
+	sk  cipher.SecKey
+	tbl *table
+	log logrus.FieldLogger
+
+	conn net.PacketConn
+}
+
+// NewBootnode creates a Bootnode identified by (pk, sk). Packets it sends
+// are signed with sk so peers can authenticate them.
+func NewBootnode(log logrus.FieldLogger, pk cipher.PubKey, sk cipher.SecKey) *Bootnode {
+	return &Bootnode{pk: pk, sk: sk, tbl: newTable(), log: log}
+}
+
+// ListenAndServe binds addr and serves discovery requests until conn is
+// closed or an unrecoverable read error occurs.
+func (b *Bootnode) ListenAndServe(addr string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	b.conn = conn
+	return b.serve()
+}
+
+func (b *Bootnode) serve() error {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, raddr, err := b.conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+
+		var pkt Packet
+		if err := decodePayload(buf[:n], &pkt); err != nil {
+			b.log.WithError(err).Debug("Discarding malformed discovery packet.")
+			continue
+		}
+		if err := pkt.Verify(); err != nil {
+			b.log.WithError(err).WithField("from", raddr).Debug("Discarding unsigned/invalid discovery packet.")
+			continue
+		}
+
+		if err := b.handle(pkt, raddr); err != nil {
+			b.log.WithError(err).WithField("from", raddr).Debug("Failed to handle discovery packet.")
+		}
+	}
+}
+
+func (b *Bootnode) handle(pkt Packet, raddr net.Addr) error {
+	switch pkt.Type {
+	case TypePing:
+		var ping Ping
+		if err := decodePayload(pkt.Payload, &ping); err != nil {
+			return err
+		}
+		// pkt.Verify already proved the packet was signed by pkt.SrcPK; a
+		// Ping claiming to announce some other PK's Node would let a single
+		// signing key forge table entries for arbitrary peers, so the
+		// announced Node must be the sender's own.
+		if ping.Node.PK != pkt.SrcPK {
+			return ErrPingNodeMismatch
+		}
+		if ping.Node.UDPAddr == "" {
+			ping.Node.UDPAddr = raddr.String()
+		}
+		b.tbl.Upsert(ping.Node)
+		return b.reply(raddr, TypePong, Pong{Node: Node{PK: b.pk, UDPAddr: b.conn.LocalAddr().String(), LastSeen: time.Now()}})
+
+	case TypeFindServers:
+		var req FindServers
+		if err := decodePayload(pkt.Payload, &req); err != nil {
+			return err
+		}
+		k := req.K
+		if k <= 0 || k > 32 {
+			k = 16
+		}
+		nodes := b.tbl.Closest(req.Target, k, pkt.SrcPK)
+		return b.reply(raddr, TypeServers, Servers{Nodes: nodes})
+
+	default:
+		return ErrUnknownType
+	}
+}
+
+func (b *Bootnode) reply(raddr net.Addr, typ byte, payload interface{}) error {
+	body, err := encodePayload(payload)
+	if err != nil {
+		return err
+	}
+	pkt := Packet{Type: typ, Payload: body}
+	if err := pkt.Sign(b.pk, b.sk); err != nil {
+		return err
+	}
+	raw, err := encodePayload(pkt)
+	if err != nil {
+		return err
+	}
+	_, err = b.conn.WriteTo(raw, raddr)
+	return err
+}
+
+// GC prunes nodes that have gone stale. Callers typically run this on a
+// timer (e.g. every staleAfter/2).
+func (b *Bootnode) GC() int {
+	return b.tbl.GC()
+}
+
+// Close stops serving and releases the underlying UDP conn.
+func (b *Bootnode) Close() error {
+	if b.conn == nil {
+		return nil
+	}
+	return b.conn.Close()
+}
@@ -0,0 +1,85 @@
+package discovery
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+func newTestBootnode(t *testing.T) (*Bootnode, *net.UDPAddr) {
+	t.Helper()
+
+	pk, sk := cipher.GenerateKeyPair()
+	bn := NewBootnode(logrus.New(), pk, sk)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	bn.conn = conn
+
+	go bn.serve() //nolint:errcheck
+
+	t.Cleanup(func() { _ = bn.Close() }) //nolint:errcheck
+
+	return bn, conn.LocalAddr().(*net.UDPAddr)
+}
+
+func sendPing(t *testing.T, cli net.PacketConn, bnAddr net.Addr, srcPK cipher.PubKey, srcSK cipher.SecKey, announced Node) {
+	t.Helper()
+
+	payload, err := encodePayload(Ping{Node: announced})
+	require.NoError(t, err)
+	pkt := Packet{Type: TypePing, Payload: payload}
+	require.NoError(t, pkt.Sign(srcPK, srcSK))
+	raw, err := encodePayload(pkt)
+	require.NoError(t, err)
+
+	_, err = cli.WriteTo(raw, bnAddr)
+	require.NoError(t, err)
+}
+
+func TestBootnode_Handle_RejectsSpoofedPingNode(t *testing.T) {
+	bn, bnAddr := newTestBootnode(t)
+
+	cli, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = cli.Close() }() //nolint:errcheck
+
+	srcPK, srcSK := cipher.GenerateKeyPair()
+	otherPK, _ := cipher.GenerateKeyPair()
+
+	// Ping signed by srcPK/srcSK but claiming to announce otherPK's Node:
+	// the bootnode must not let this forge an entry for otherPK.
+	sendPing(t, cli, bnAddr, srcPK, srcSK, Node{PK: otherPK, UDPAddr: "1.2.3.4:5"})
+	// The spoofed ping itself upserts nothing, so a genuine ping sent after
+	// it on the same socket marks that the bootnode has handled both.
+	sendPing(t, cli, bnAddr, srcPK, srcSK, Node{PK: srcPK, UDPAddr: "1.2.3.4:5"})
+
+	require.Eventually(t, func() bool {
+		_, ok := bn.tbl.Get(srcPK)
+		return ok
+	}, time.Second, 10*time.Millisecond, "ping never handled")
+
+	_, ok := bn.tbl.Get(otherPK)
+	require.False(t, ok, "spoofed Node.PK must not be upserted into the table")
+}
+
+func TestBootnode_Handle_AcceptsGenuinePing(t *testing.T) {
+	bn, bnAddr := newTestBootnode(t)
+
+	cli, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = cli.Close() }() //nolint:errcheck
+
+	srcPK, srcSK := cipher.GenerateKeyPair()
+	sendPing(t, cli, bnAddr, srcPK, srcSK, Node{PK: srcPK, UDPAddr: "1.2.3.4:5"})
+
+	require.Eventually(t, func() bool {
+		n, ok := bn.tbl.Get(srcPK)
+		return ok && n.UDPAddr == "1.2.3.4:5"
+	}, time.Second, 10*time.Millisecond, "genuine ping was never upserted")
+}
@@ -0,0 +1,106 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+// queryTimeout bounds how long a single bootnode query waits for a reply
+// before giving up.
+const queryTimeout = 5 * time.Second
+
+// Client queries one or more bootnodes to resolve live dmsg servers,
+// without needing a static server list baked into dmsg.Client's config.
+type Client struct {
+	pk        cipher.PubKey
+	sk        cipher.SecKey
+	bootnodes []string
+}
+
+// NewClient creates a discovery Client that signs its requests with
+// (pk, sk) and queries the given bootnode UDP addresses.
+func NewClient(pk cipher.PubKey, sk cipher.SecKey, bootnodes []string) *Client {
+	return &Client{pk: pk, sk: sk, bootnodes: bootnodes}
+}
+
+// FindServers asks the configured bootnodes, in order, for up to k dmsg
+// servers near target's public key, returning the first successful
+// response. Callers feed the result into dmsg's pluggable SessionTransport
+// layer to dial one of the returned nodes.
+func (c *Client) FindServers(ctx context.Context, target cipher.PubKey, k int) ([]Node, error) {
+	var lastErr error
+	for _, bootnode := range c.bootnodes {
+		nodes, err := c.query(ctx, bootnode, target, k)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return nodes, nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no bootnodes configured")
+	}
+	return nil, fmt.Errorf("discovery: all bootnodes failed: %w", lastErr)
+}
+
+func (c *Client) query(ctx context.Context, bootnode string, target cipher.PubKey, k int) ([]Node, error) {
+	raddr, err := net.ResolveUDPAddr("udp", bootnode)
+	if err != nil {
+		return nil, fmt.Errorf("resolve bootnode address %s: %w", bootnode, err)
+	}
+
+	conn, err := net.DialUDP("udp", nil, raddr)
+	if err != nil {
+		return nil, fmt.Errorf("dial bootnode %s: %w", bootnode, err)
+	}
+	defer func() { _ = conn.Close() }() //nolint:errcheck
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline) //nolint:errcheck
+	} else {
+		_ = conn.SetDeadline(time.Now().Add(queryTimeout)) //nolint:errcheck
+	}
+
+	body, err := encodePayload(FindServers{Target: target, K: k})
+	if err != nil {
+		return nil, err
+	}
+	pkt := Packet{Type: TypeFindServers, Payload: body}
+	if err := pkt.Sign(c.pk, c.sk); err != nil {
+		return nil, err
+	}
+	raw, err := encodePayload(pkt)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(raw); err != nil {
+		return nil, fmt.Errorf("send FindServers to %s: %w", bootnode, err)
+	}
+
+	buf := make([]byte, maxPacketSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, fmt.Errorf("read reply from %s: %w", bootnode, err)
+	}
+
+	var reply Packet
+	if err := decodePayload(buf[:n], &reply); err != nil {
+		return nil, fmt.Errorf("decode reply from %s: %w", bootnode, err)
+	}
+	if err := reply.Verify(); err != nil {
+		return nil, fmt.Errorf("reply from %s failed signature check: %w", bootnode, err)
+	}
+	if reply.Type != TypeServers {
+		return nil, fmt.Errorf("unexpected reply type %d from %s", reply.Type, bootnode)
+	}
+
+	var servers Servers
+	if err := decodePayload(reply.Payload, &servers); err != nil {
+		return nil, err
+	}
+	return servers.Nodes, nil
+}
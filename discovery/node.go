@@ -0,0 +1,46 @@
+// Package discovery implements a lightweight, Kademlia-style UDP protocol
+// that lets dmsg clients find live dmsg servers by querying a handful of
+// well-known bootnodes instead of relying on a static server list, in the
+// same vein as the Ethereum discovery protocol's bootnodes.
+package discovery
+
+import (
+	"time"
+
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+// Node is what a bootnode remembers about a dmsg server: its identity, the
+// UDP endpoint it is reachable at for the discovery protocol itself, and
+// the session-layer endpoints it advertises (TCP/STCPR/SUDPH - whichever
+// it supports).
+type Node struct {
+	PK        cipher.PubKey `json:"pk"`
+	UDPAddr   string        `json:"udp_addr"`
+	TCPAddr   string        `json:"tcp_addr,omitempty"`
+	STCPRAddr string        `json:"stcpr_addr,omitempty"`
+	SUDPHAddr string        `json:"sudph_addr,omitempty"`
+	LastSeen  time.Time     `json:"last_seen"`
+}
+
+// distance returns the XOR distance between two public keys, treated as
+// big-endian integers, used to rank nodes by closeness to a target for
+// FindServers responses.
+func distance(a, b cipher.PubKey) [len(cipher.PubKey{})]byte {
+	var d [len(cipher.PubKey{})]byte
+	for i := range d {
+		d[i] = a[i] ^ b[i]
+	}
+	return d
+}
+
+// less reports whether distance x is strictly smaller than y, comparing
+// both as big-endian integers.
+func less(x, y [len(cipher.PubKey{})]byte) bool {
+	for i := range x {
+		if x[i] != y[i] {
+			return x[i] < y[i]
+		}
+	}
+	return false
+}
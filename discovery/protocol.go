@@ -0,0 +1,127 @@
+package discovery
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"time"
+
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+// Packet types exchanged by the discovery protocol. Each is signed by the
+// sender's secret key so a bootnode cannot forge entries on another node's
+// behalf.
+const (
+	TypePing        byte = iota // Ping: "are you alive, and here is how to reach me."
+	TypePong                    // Pong: reply to Ping.
+	TypeFindServers             // FindServers: "send me up to K nodes near this target PK."
+	TypeServers                 // Servers: reply to FindServers.
+)
+
+var (
+	// ErrInvalidSig is returned when a packet's signature does not match its claimed sender.
+	ErrInvalidSig = errors.New("discovery: invalid packet signature")
+	// ErrUnknownType is returned when a packet's Type field is not recognised.
+	ErrUnknownType = errors.New("discovery: unknown packet type")
+	// ErrPingNodeMismatch is returned when a Ping's announced Node.PK does
+	// not match the packet's signature-verified SrcPK.
+	ErrPingNodeMismatch = errors.New("discovery: ping node PK does not match packet signer")
+	// ErrPacketStale is returned when a packet's Timestamp falls outside
+	// packetFreshnessWindow of the verifier's clock.
+	ErrPacketStale = errors.New("discovery: packet timestamp outside freshness window")
+)
+
+// packetFreshnessWindow bounds how far a packet's signed Timestamp may
+// drift from the verifier's own clock, in either direction, before Verify
+// rejects it as stale. This keeps a captured, validly-signed packet from
+// being replayed indefinitely - without it, a replayed Ping could keep a
+// dead node alive in a bootnode's table past GC's staleAfter.
+const packetFreshnessWindow = 30 * time.Second
+
+// Packet is the envelope for every message sent over the discovery
+// protocol. Payload is a gob-encoded Ping, Pong, FindServers or Servers,
+// selected by Type.
+type Packet struct {
+	Type      byte
+	SrcPK     cipher.PubKey
+	Timestamp int64
+	Payload   []byte
+	Sig       cipher.Sig
+}
+
+// signingBytes returns the bytes that Sig is computed over: every field of
+// the packet except Sig itself.
+func (p Packet) signingBytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(p.Type)
+	buf.Write(p.SrcPK[:])
+	ts := make([]byte, 8)
+	for i := 0; i < 8; i++ {
+		ts[i] = byte(p.Timestamp >> (56 - 8*i))
+	}
+	buf.Write(ts)
+	buf.Write(p.Payload)
+	return buf.Bytes()
+}
+
+// Sign fills in SrcPK, Timestamp and Sig from sk and the local public key.
+func (p *Packet) Sign(pk cipher.PubKey, sk cipher.SecKey) error {
+	p.SrcPK = pk
+	p.Timestamp = time.Now().UnixNano()
+	sig, err := cipher.SignPayload(p.signingBytes(), sk)
+	if err != nil {
+		return err
+	}
+	p.Sig = sig
+	return nil
+}
+
+// Verify checks that Sig is a valid signature by SrcPK over the packet's
+// other fields, and that Timestamp is within packetFreshnessWindow of now,
+// so a captured packet cannot be replayed indefinitely.
+func (p Packet) Verify() error {
+	if err := cipher.VerifyPubKeySignedPayload(p.SrcPK, p.Sig, p.signingBytes()); err != nil {
+		return ErrInvalidSig
+	}
+	age := time.Since(time.Unix(0, p.Timestamp))
+	if age < -packetFreshnessWindow || age > packetFreshnessWindow {
+		return ErrPacketStale
+	}
+	return nil
+}
+
+// Ping announces the sender's identity and discovery-protocol address.
+type Ping struct {
+	Node Node
+}
+
+// Pong is the reply to a Ping, echoing the responder's own Node record so
+// the pinger can learn about it too.
+type Pong struct {
+	Node Node
+}
+
+// FindServers asks the receiver to return up to K nodes nearest to Target
+// by XOR distance.
+type FindServers struct {
+	Target cipher.PubKey
+	K      int
+}
+
+// Servers is the reply to FindServers.
+type Servers struct {
+	Nodes []Node
+}
+
+func encodePayload(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodePayload(b []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(b)).Decode(v)
+}
@@ -0,0 +1,32 @@
+package discovery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+func TestPacket_Verify_RejectsStaleTimestamp(t *testing.T) {
+	pk, sk := cipher.GenerateKeyPair()
+
+	pkt := Packet{Type: TypePing, Timestamp: time.Now().Add(-time.Hour).UnixNano()}
+	pkt.SrcPK = pk
+	sig, err := cipher.SignPayload(pkt.signingBytes(), sk)
+	require.NoError(t, err)
+	pkt.Sig = sig
+
+	require.ErrorIs(t, pkt.Verify(), ErrPacketStale)
+}
+
+func TestPacket_Verify_AcceptsFreshTimestamp(t *testing.T) {
+	pk, sk := cipher.GenerateKeyPair()
+
+	var pkt Packet
+	pkt.Type = TypePing
+	require.NoError(t, pkt.Sign(pk, sk))
+
+	require.NoError(t, pkt.Verify())
+}
@@ -0,0 +1,83 @@
+package discovery
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+// staleAfter is how long a node can go unseen before a bootnode drops it
+// from its table.
+const staleAfter = 10 * time.Minute
+
+// table is an in-memory, ageing store of known Nodes, keyed by public key.
+// It is deliberately simpler than a full Kademlia k-bucket tree: for the
+// node counts a handful of bootnodes need to track, a single map plus a
+// linear XOR-distance sort on lookup is sufficient, and avoids the
+// complexity of per-bucket splitting.
+type table struct {
+	mu    sync.RWMutex
+	nodes map[cipher.PubKey]Node
+}
+
+func newTable() *table {
+	return &table{nodes: make(map[cipher.PubKey]Node)}
+}
+
+// Upsert records or refreshes a node's entry.
+func (t *table) Upsert(n Node) {
+	n.LastSeen = time.Now()
+	t.mu.Lock()
+	t.nodes[n.PK] = n
+	t.mu.Unlock()
+}
+
+// Get returns a node by its public key.
+func (t *table) Get(pk cipher.PubKey) (Node, bool) {
+	t.mu.RLock()
+	n, ok := t.nodes[pk]
+	t.mu.RUnlock()
+	return n, ok
+}
+
+// Closest returns up to k nodes nearest to target by XOR distance,
+// excluding exclude itself.
+func (t *table) Closest(target cipher.PubKey, k int, exclude cipher.PubKey) []Node {
+	t.mu.RLock()
+	all := make([]Node, 0, len(t.nodes))
+	for pk, n := range t.nodes {
+		if pk != exclude {
+			all = append(all, n)
+		}
+	}
+	t.mu.RUnlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		return less(distance(target, all[i].PK), distance(target, all[j].PK))
+	})
+
+	if len(all) > k {
+		all = all[:k]
+	}
+	return all
+}
+
+// GC removes nodes that have not been seen within staleAfter, returning how
+// many were dropped.
+func (t *table) GC() int {
+	cutoff := time.Now().Add(-staleAfter)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	removed := 0
+	for pk, n := range t.nodes {
+		if n.LastSeen.Before(cutoff) {
+			delete(t.nodes, pk)
+			removed++
+		}
+	}
+	return removed
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/SkycoinProject/dmsg/cipher"
 )
@@ -15,9 +16,29 @@ type Whitelist interface {
 	Get(pk cipher.PubKey) (bool, error)
 	All() (map[cipher.PubKey]bool, error)
 	Add(pks ...cipher.PubKey) error
+	// AddWithTTL whitelists pks until expiry. A zero expiry means the
+	// entries never expire, equivalent to Add.
+	AddWithTTL(expiry time.Time, pks ...cipher.PubKey) error
 	Remove(pks ...cipher.PubKey) error
 }
 
+// TTLCollector is implemented by Whitelist backends that store expiring
+// entries and need their expired entries pruned out-of-band (e.g. on a
+// timer), rather than relying solely on lazy expiry checks in Get/All.
+type TTLCollector interface {
+	// GC removes expired entries and reports how many were removed.
+	GC() (removed int, err error)
+}
+
+// entry is whitelist state common to backends that support TTL expiry.
+type entry struct {
+	Expiry time.Time `json:"expiry,omitempty"` // zero value means no expiry.
+}
+
+func (e entry) expired(now time.Time) bool {
+	return !e.Expiry.IsZero() && now.After(e.Expiry)
+}
+
 // NewJSONFileWhiteList creates a JSON file implementation of a whitelist.
 func NewJSONFileWhiteList(fileName string) (Whitelist, error) {
 	fileName, err := filepath.Abs(fileName)
@@ -38,33 +59,43 @@ type jsonFileWhitelist struct {
 
 func (w *jsonFileWhitelist) Get(pk cipher.PubKey) (bool, error) {
 	var ok bool
-	err := w.open(os.O_RDONLY|os.O_CREATE, func(pkMap map[cipher.PubKey]bool, _ *os.File) error {
-		ok = pkMap[pk]
+	err := w.open(os.O_RDONLY|os.O_CREATE, false, func(pkMap map[cipher.PubKey]entry, _ *os.File) error {
+		e, found := pkMap[pk]
+		ok = found && !e.expired(time.Now())
 		return nil
 	})
 	return ok, jsonFileErr(err)
 }
 
 func (w *jsonFileWhitelist) All() (map[cipher.PubKey]bool, error) {
-	var out map[cipher.PubKey]bool
-	err := w.open(os.O_RDONLY|os.O_CREATE, func(pkMap map[cipher.PubKey]bool, _ *os.File) error {
-		out = pkMap
+	out := make(map[cipher.PubKey]bool)
+	err := w.open(os.O_RDONLY|os.O_CREATE, false, func(pkMap map[cipher.PubKey]entry, _ *os.File) error {
+		now := time.Now()
+		for pk, e := range pkMap {
+			if !e.expired(now) {
+				out[pk] = true
+			}
+		}
 		return nil
 	})
 	return out, jsonFileErr(err)
 }
 
 func (w *jsonFileWhitelist) Add(pks ...cipher.PubKey) error {
-	return jsonFileErr(w.open(os.O_RDWR|os.O_CREATE, func(pkMap map[cipher.PubKey]bool, f *os.File) error {
+	return w.AddWithTTL(time.Time{}, pks...)
+}
+
+func (w *jsonFileWhitelist) AddWithTTL(expiry time.Time, pks ...cipher.PubKey) error {
+	return jsonFileErr(w.open(os.O_RDWR|os.O_CREATE, true, func(pkMap map[cipher.PubKey]entry, f *os.File) error {
 		for _, pk := range pks {
-			pkMap[pk] = true
+			pkMap[pk] = entry{Expiry: expiry}
 		}
 		return json.NewEncoder(f).Encode(pkMap)
 	}))
 }
 
 func (w *jsonFileWhitelist) Remove(pks ...cipher.PubKey) error {
-	return jsonFileErr(w.open(os.O_RDWR|os.O_CREATE, func(pkMap map[cipher.PubKey]bool, f *os.File) error {
+	return jsonFileErr(w.open(os.O_RDWR|os.O_CREATE, true, func(pkMap map[cipher.PubKey]entry, f *os.File) error {
 		for _, pk := range pks {
 			delete(pkMap, pk)
 		}
@@ -72,7 +103,27 @@ func (w *jsonFileWhitelist) Remove(pks ...cipher.PubKey) error {
 	}))
 }
 
-func (w *jsonFileWhitelist) open(perm int, fn func(pkMap map[cipher.PubKey]bool, f *os.File) error) error {
+// GC prunes expired entries from the JSON file.
+func (w *jsonFileWhitelist) GC() (removed int, err error) {
+	err = jsonFileErr(w.open(os.O_RDWR|os.O_CREATE, true, func(pkMap map[cipher.PubKey]entry, f *os.File) error {
+		now := time.Now()
+		for pk, e := range pkMap {
+			if e.expired(now) {
+				delete(pkMap, pk)
+				removed++
+			}
+		}
+		return json.NewEncoder(f).Encode(pkMap)
+	}))
+	return removed, err
+}
+
+// open opens the whitelist file with perm, decodes its current contents
+// and calls fn with them. write must be true for callers that have fn
+// write a new version of the file back out (Add/Remove/GC); read-only
+// callers (Get/All) must pass false, since truncating a file opened
+// O_RDONLY fails.
+func (w *jsonFileWhitelist) open(perm int, write bool, fn func(pkMap map[cipher.PubKey]entry, f *os.File) error) error {
 	f, err := os.OpenFile(w.fileName, perm, 0600)
 	if err != nil {
 		return err
@@ -86,16 +137,21 @@ func (w *jsonFileWhitelist) open(perm int, fn func(pkMap map[cipher.PubKey]bool,
 	}
 
 	// read public key map from file
-	pks := make(map[cipher.PubKey]bool)
+	pks := make(map[cipher.PubKey]entry)
 	if info.Size() > 0 {
 		if err := json.NewDecoder(f).Decode(&pks); err != nil {
 			return err
 		}
 	}
 
-	// seek back to start of file
-	if _, err := f.Seek(0, 0); err != nil {
-		return err
+	if write {
+		// seek back to start of file so fn's Encode overwrites, not appends
+		if _, err := f.Seek(0, 0); err != nil {
+			return err
+		}
+		if err := f.Truncate(0); err != nil {
+			return err
+		}
 	}
 
 	return fn(pks, f)
@@ -111,36 +167,43 @@ func jsonFileErr(err error) error {
 // NewMemoryWhitelist creates a memory implementation of a whitelist.
 func NewMemoryWhitelist() Whitelist {
 	return &memoryWhitelist{
-		m: make(map[cipher.PubKey]struct{}),
+		m: make(map[cipher.PubKey]entry),
 	}
 }
 
 type memoryWhitelist struct {
-	m   map[cipher.PubKey]struct{}
+	m   map[cipher.PubKey]entry
 	mux sync.RWMutex
 }
 
 func (w *memoryWhitelist) Get(pk cipher.PubKey) (bool, error) {
 	w.mux.RLock()
-	_, ok := w.m[pk]
+	e, ok := w.m[pk]
 	w.mux.RUnlock()
-	return ok, nil
+	return ok && !e.expired(time.Now()), nil
 }
 
 func (w *memoryWhitelist) All() (map[cipher.PubKey]bool, error) {
 	out := make(map[cipher.PubKey]bool)
+	now := time.Now()
 	w.mux.RLock()
-	for k := range w.m {
-		out[k] = true
+	for pk, e := range w.m {
+		if !e.expired(now) {
+			out[pk] = true
+		}
 	}
 	w.mux.RUnlock()
 	return out, nil
 }
 
 func (w *memoryWhitelist) Add(pks ...cipher.PubKey) error {
+	return w.AddWithTTL(time.Time{}, pks...)
+}
+
+func (w *memoryWhitelist) AddWithTTL(expiry time.Time, pks ...cipher.PubKey) error {
 	w.mux.Lock()
 	for _, pk := range pks {
-		w.m[pk] = struct{}{}
+		w.m[pk] = entry{Expiry: expiry}
 	}
 	w.mux.Unlock()
 	return nil
@@ -153,4 +216,18 @@ func (w *memoryWhitelist) Remove(pks ...cipher.PubKey) error {
 	}
 	w.mux.Unlock()
 	return nil
-}
\ No newline at end of file
+}
+
+// GC prunes expired entries from the in-memory map.
+func (w *memoryWhitelist) GC() (removed int, err error) {
+	now := time.Now()
+	w.mux.Lock()
+	for pk, e := range w.m {
+		if e.expired(now) {
+			delete(w.m, pk)
+			removed++
+		}
+	}
+	w.mux.Unlock()
+	return removed, nil
+}
@@ -0,0 +1,152 @@
+package dmsgpty
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+// whitelistBucket is the sole BoltDB bucket used to store whitelist entries,
+// keyed by raw public key bytes.
+var whitelistBucket = []byte("whitelist")
+
+// boltWhitelist is a BoltDB-backed Whitelist. Unlike jsonFileWhitelist, it
+// does not deserialize/reserialize the entire whitelist on every mutation:
+// each Add/Remove/GC only touches the keys it needs to.
+type boltWhitelist struct {
+	db *bbolt.DB
+}
+
+// NewBoltWhitelist creates a BoltDB-backed Whitelist at path, creating the
+// file and bucket if they do not already exist.
+func NewBoltWhitelist(path string) (Whitelist, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("bolt whitelist: open %s: %v", path, err)
+	}
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(whitelistBucket)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("bolt whitelist: init bucket: %v", err)
+	}
+	return &boltWhitelist{db: db}, nil
+}
+
+func (w *boltWhitelist) Get(pk cipher.PubKey) (bool, error) {
+	var ok bool
+	err := w.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(whitelistBucket).Get(pk[:])
+		if v == nil {
+			return nil
+		}
+		var e entry
+		if err := json.Unmarshal(v, &e); err != nil {
+			return err
+		}
+		ok = !e.expired(time.Now())
+		return nil
+	})
+	return ok, boltErr(err)
+}
+
+func (w *boltWhitelist) All() (map[cipher.PubKey]bool, error) {
+	out := make(map[cipher.PubKey]bool)
+	now := time.Now()
+	err := w.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(whitelistBucket).ForEach(func(k, v []byte) error {
+			var e entry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			if e.expired(now) {
+				return nil
+			}
+			var pk cipher.PubKey
+			copy(pk[:], k)
+			out[pk] = true
+			return nil
+		})
+	})
+	return out, boltErr(err)
+}
+
+func (w *boltWhitelist) Add(pks ...cipher.PubKey) error {
+	return w.AddWithTTL(time.Time{}, pks...)
+}
+
+func (w *boltWhitelist) AddWithTTL(expiry time.Time, pks ...cipher.PubKey) error {
+	return boltErr(w.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(whitelistBucket)
+		for _, pk := range pks {
+			v, err := json.Marshal(entry{Expiry: expiry})
+			if err != nil {
+				return err
+			}
+			if err := b.Put(pk[:], v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+func (w *boltWhitelist) Remove(pks ...cipher.PubKey) error {
+	return boltErr(w.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(whitelistBucket)
+		for _, pk := range pks {
+			if err := b.Delete(pk[:]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+// GC removes expired entries, visiting only the bucket's keys rather than
+// rewriting the whole store.
+func (w *boltWhitelist) GC() (removed int, err error) {
+	err = boltErr(w.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(whitelistBucket)
+		now := time.Now()
+
+		var expired [][]byte
+		if err := b.ForEach(func(k, v []byte) error {
+			var e entry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			if e.expired(now) {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+			removed++
+		}
+		return nil
+	}))
+	return removed, err
+}
+
+// Close releases the underlying BoltDB file handle.
+func (w *boltWhitelist) Close() error {
+	return w.db.Close()
+}
+
+func boltErr(err error) error {
+	if err != nil {
+		return fmt.Errorf("bolt whitelist: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package dmsgpty
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+// MigrateJSONWhitelist reads an existing JSON whitelist file (as produced by
+// NewJSONFileWhiteList, in either its legacy map[pk]bool shape or the
+// current map[pk]entry shape) and adds every non-expired entry it contains
+// to dst, preserving each entry's expiry.
+func MigrateJSONWhitelist(jsonFileName string, dst Whitelist) error {
+	f, err := os.Open(jsonFileName) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("migrate whitelist: open %s: %v", jsonFileName, err)
+	}
+	defer func() { _ = f.Close() }() //nolint:errcheck
+
+	var pkMap map[string]json.RawMessage
+	if err := json.NewDecoder(f).Decode(&pkMap); err != nil {
+		return fmt.Errorf("migrate whitelist: decode %s: %v", jsonFileName, err)
+	}
+
+	now := time.Now()
+	for hexPK, raw := range pkMap {
+		var pk cipher.PubKey
+		if err := pk.UnmarshalText([]byte(hexPK)); err != nil {
+			return fmt.Errorf("migrate whitelist: invalid public key %s: %v", hexPK, err)
+		}
+
+		// Legacy files store `true`/`false`; current files store an entry object.
+		var e entry
+		if err := json.Unmarshal(raw, &e); err != nil {
+			var whitelisted bool
+			if err := json.Unmarshal(raw, &whitelisted); err != nil || !whitelisted {
+				continue
+			}
+		}
+		if e.expired(now) {
+			continue
+		}
+
+		if err := dst.AddWithTTL(e.Expiry, pk); err != nil {
+			return fmt.Errorf("migrate whitelist: add %s: %v", hexPK, err)
+		}
+	}
+	return nil
+}
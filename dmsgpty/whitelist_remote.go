@@ -0,0 +1,138 @@
+package dmsgpty
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+// remoteWhitelistEntry is the wire format used between remoteWhitelist and
+// the whitelist server: GET /whitelist returns a list of these.
+type remoteWhitelistEntry struct {
+	PK     cipher.PubKey `json:"pk"`
+	Expiry time.Time     `json:"expiry,omitempty"`
+}
+
+// remoteWhitelist is a Whitelist backed by a central whitelist service,
+// reached over HTTP, so a fleet of dmsgpty hosts can share one source of
+// truth instead of each keeping its own local state.
+type remoteWhitelist struct {
+	baseURL string
+	auth    string // bearer token sent as "Authorization: Bearer <auth>".
+	client  *http.Client
+}
+
+// NewRemoteWhitelist creates a Whitelist backed by the whitelist service
+// reachable at baseURL, authenticating requests with a bearer token.
+func NewRemoteWhitelist(baseURL, auth string) Whitelist {
+	return &remoteWhitelist{
+		baseURL: baseURL,
+		auth:    auth,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (w *remoteWhitelist) Get(pk cipher.PubKey) (bool, error) {
+	all, err := w.All()
+	if err != nil {
+		return false, err
+	}
+	return all[pk], nil
+}
+
+func (w *remoteWhitelist) All() (map[cipher.PubKey]bool, error) {
+	req, err := http.NewRequest(http.MethodGet, w.baseURL+"/whitelist", nil)
+	if err != nil {
+		return nil, err
+	}
+	w.authorize(req)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("remote whitelist: GET /whitelist: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("remote whitelist: GET /whitelist: unexpected status %s", resp.Status)
+	}
+
+	var entries []remoteWhitelistEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("remote whitelist: decode response: %v", err)
+	}
+
+	now := time.Now()
+	out := make(map[cipher.PubKey]bool, len(entries))
+	for _, e := range entries {
+		if e.Expiry.IsZero() || now.Before(e.Expiry) {
+			out[e.PK] = true
+		}
+	}
+	return out, nil
+}
+
+func (w *remoteWhitelist) Add(pks ...cipher.PubKey) error {
+	return w.AddWithTTL(time.Time{}, pks...)
+}
+
+func (w *remoteWhitelist) AddWithTTL(expiry time.Time, pks ...cipher.PubKey) error {
+	entries := make([]remoteWhitelistEntry, len(pks))
+	for i, pk := range pks {
+		entries[i] = remoteWhitelistEntry{PK: pk, Expiry: expiry}
+	}
+
+	body, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.baseURL+"/whitelist", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	w.authorize(req)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("remote whitelist: POST /whitelist: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("remote whitelist: POST /whitelist: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+func (w *remoteWhitelist) Remove(pks ...cipher.PubKey) error {
+	for _, pk := range pks {
+		req, err := http.NewRequest(http.MethodDelete, w.baseURL+"/whitelist/"+pk.Hex(), nil)
+		if err != nil {
+			return err
+		}
+		w.authorize(req)
+
+		resp, err := w.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("remote whitelist: DELETE /whitelist/%s: %v", pk, err)
+		}
+		_ = resp.Body.Close() //nolint:errcheck
+
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+			return fmt.Errorf("remote whitelist: DELETE /whitelist/%s: unexpected status %s", pk, resp.Status)
+		}
+	}
+	return nil
+}
+
+func (w *remoteWhitelist) authorize(req *http.Request) {
+	if w.auth != "" {
+		req.Header.Set("Authorization", "Bearer "+w.auth)
+	}
+}
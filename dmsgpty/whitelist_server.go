@@ -0,0 +1,115 @@
+package dmsgpty
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+// WhitelistServer is a reference HTTP server exposing a Whitelist over the
+// same protocol spoken by remoteWhitelist, so a fleet of dmsgpty hosts can
+// point their NewRemoteWhitelist client at one shared instance of it.
+//
+//	GET    /whitelist          -> []remoteWhitelistEntry
+//	POST   /whitelist          <- []remoteWhitelistEntry, whitelists them
+//	DELETE /whitelist/{pk}     -> removes a single entry
+//
+// All endpoints require a "Authorization: Bearer <token>" header matching
+// the configured auth token. An empty auth token disables this check.
+type WhitelistServer struct {
+	wl   Whitelist
+	auth string
+}
+
+// NewWhitelistServer creates a WhitelistServer backed by wl, authenticating
+// requests against a single bearer token.
+func NewWhitelistServer(wl Whitelist, auth string) *WhitelistServer {
+	return &WhitelistServer{wl: wl, auth: auth}
+}
+
+// Handler returns the http.Handler to mount at the root of a listener.
+func (s *WhitelistServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/whitelist", s.authenticated(s.handleWhitelist))
+	mux.HandleFunc("/whitelist/", s.authenticated(s.handleWhitelistEntry))
+	return mux
+}
+
+func (s *WhitelistServer) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.auth != "" {
+			const prefix = "Bearer "
+			h := r.Header.Get("Authorization")
+			if !strings.HasPrefix(h, prefix) || h[len(prefix):] != s.auth {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+func (s *WhitelistServer) handleWhitelist(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		all, err := s.wl.All()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		entries := make([]remoteWhitelistEntry, 0, len(all))
+		for pk := range all {
+			entries = append(entries, remoteWhitelistEntry{PK: pk})
+		}
+		writeJSON(w, entries)
+
+	case http.MethodPost:
+		var entries []remoteWhitelistEntry
+		if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Group by expiry so entries sharing a TTL are added together, as
+		// AddWithTTL applies a single expiry to all given keys.
+		byExpiry := make(map[time.Time][]cipher.PubKey)
+		for _, e := range entries {
+			byExpiry[e.Expiry] = append(byExpiry[e.Expiry], e.PK)
+		}
+		for expiry, pks := range byExpiry {
+			if err := s.wl.AddWithTTL(expiry, pks...); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *WhitelistServer) handleWhitelistEntry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	hexPK := strings.TrimPrefix(r.URL.Path, "/whitelist/")
+	var pk cipher.PubKey
+	if err := pk.UnmarshalText([]byte(hexPK)); err != nil {
+		http.Error(w, "invalid public key: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.wl.Remove(pk); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v) //nolint:errcheck
+}
@@ -0,0 +1,77 @@
+package dmsgpty
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+func TestMemoryWhitelist_TTL(t *testing.T) {
+	wl := NewMemoryWhitelist()
+	pk, _ := cipher.GenerateKeyPair()
+
+	require.NoError(t, wl.AddWithTTL(time.Now().Add(-time.Minute), pk))
+
+	ok, err := wl.Get(pk)
+	require.NoError(t, err)
+	require.False(t, ok, "expired entry should not be whitelisted")
+
+	all, err := wl.All()
+	require.NoError(t, err)
+	require.NotContains(t, all, pk)
+
+	gc, ok := wl.(TTLCollector)
+	require.True(t, ok)
+	removed, err := gc.GC()
+	require.NoError(t, err)
+	require.Equal(t, 1, removed)
+}
+
+func TestMemoryWhitelist_AddIsPermanent(t *testing.T) {
+	wl := NewMemoryWhitelist()
+	pk, _ := cipher.GenerateKeyPair()
+
+	require.NoError(t, wl.Add(pk))
+
+	ok, err := wl.Get(pk)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestJSONFileWhitelist_RoundTrip(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "whitelist.json")
+	wl, err := NewJSONFileWhiteList(fileName)
+	require.NoError(t, err)
+
+	pk, _ := cipher.GenerateKeyPair()
+
+	// Get/All on a file that doesn't exist yet must not error (this is the
+	// O_RDONLY|O_CREATE read path; it must never attempt to truncate).
+	ok, err := wl.Get(pk)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	all, err := wl.All()
+	require.NoError(t, err)
+	require.Empty(t, all)
+
+	require.NoError(t, wl.Add(pk))
+
+	ok, err = wl.Get(pk)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	all, err = wl.All()
+	require.NoError(t, err)
+	require.True(t, all[pk])
+
+	require.NoError(t, wl.Remove(pk))
+
+	ok, err = wl.Get(pk)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
@@ -0,0 +1,357 @@
+package dmsg
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+// tlsRecordHeaderLen is the length of a TLS record header: type(1) + version(2) + length(2).
+const tlsRecordHeaderLen = 5
+
+// maxAppRecordSize mirrors TLS's 16KiB record size cap, so chunked yamux
+// frames look like ordinary TLS application data to a box only inspecting
+// record headers and lengths.
+const maxAppRecordSize = 16384
+
+// TLS content types, versions and handshake types used to shape the
+// synthetic records. These are wire-format constants, not a TLS
+// implementation: dmsg never negotiates cipher suites or validates
+// certificates, it only borrows the byte shapes.
+const (
+	tlsContentHandshake     byte = 22
+	tlsContentChangeCipher  byte = 20
+	tlsContentApplication   byte = 23
+	tlsVersionMajor         byte = 3
+	tlsVersionMinorTLS12    byte = 3
+	tlsHandshakeClientHello byte = 1
+	tlsHandshakeServerHello byte = 2
+	tlsHandshakeFinished    byte = 20
+
+	tlsClientHelloRandomLen = 32
+	tlsClientHelloSessIDLen = 32
+	pskTagLen               = 16
+)
+
+// Obfuscator disguises the Noise XK session handshake used by
+// InitiateSession/RespondSession as a TLS 1.2 handshake, so that a passive
+// observer or DPI box sees a ClientHello -> ServerHello+ChangeCipherSpec+
+// Finished exchange rather than a dmsg session being set up. Once the
+// handshake completes, subsequent yamux frames are still chunked into
+// TLS-application-data-shaped records, so the whole connection continues
+// to look like ordinary TLS traffic to anything only inspecting record
+// headers.
+//
+// This is modelled after Cloak-style censorship-resistant transports and
+// is meant to let dmsg nodes share a listening port (e.g. 443) with real
+// TLS traffic.
+type Obfuscator interface {
+	// WrapInitiator disguises the initiator side of conn. rPK is the
+	// responder's public key, used to derive the pre-shared tag that
+	// authenticates the synthetic ClientHello.
+	WrapInitiator(conn net.Conn, rPK cipher.PubKey) net.Conn
+	// WrapResponder disguises the responder side of conn. lPK is the
+	// responder's own public key.
+	WrapResponder(conn net.Conn, lPK cipher.PubKey) net.Conn
+}
+
+// NewTLSObfuscator returns an Obfuscator that mimics a TLS 1.2 handshake.
+func NewTLSObfuscator() Obfuscator {
+	return tlsObfuscator{}
+}
+
+type tlsObfuscator struct{}
+
+func (tlsObfuscator) WrapInitiator(conn net.Conn, rPK cipher.PubKey) net.Conn {
+	return &obfuscatedConn{Conn: conn, anchorPK: rPK, initiator: true}
+}
+
+func (tlsObfuscator) WrapResponder(conn net.Conn, lPK cipher.PubKey) net.Conn {
+	return &obfuscatedConn{Conn: conn, anchorPK: lPK, initiator: false}
+}
+
+// pskTag derives a MAC tag from pk, used to authenticate the synthetic
+// ClientHello/ServerHello fields against a peer that shares no prior
+// secret beyond the responder's already-known public key.
+func pskTag(pk cipher.PubKey, msg []byte) []byte {
+	mac := hmac.New(sha256.New, pk[:])
+	mac.Write(msg) //nolint:errcheck
+	return mac.Sum(nil)[:pskTagLen]
+}
+
+// Config groups dmsg-client-level settings that affect session
+// establishment.
+type Config struct {
+	// Obfuscator, if set, disguises the Noise XK session handshake run by
+	// InitiateSession/RespondSession as a TLS handshake. Callers apply it
+	// themselves, by wrapping the conn with Obfuscator.WrapInitiator /
+	// WrapResponder before handing it to InitiateSession/RespondSession.
+	Obfuscator Obfuscator
+}
+
+// obfuscatedConn wraps a net.Conn so that the first handshake message each
+// side writes/reads is framed as a TLS handshake record (or record trio,
+// for the responder's ServerHello/ChangeCipherSpec/Finished flight), and
+// every message after that is chunked into TLS-application-data-shaped
+// records.
+//
+// This relies on the Noise XK handshake performing exactly one conn.Write
+// per message it sends and exactly one conn.Read per message it expects
+// (three messages total: -> e, <- e,ee,s,es, -> s,se) before any
+// application data flows. If a handshake implementation ever splits a
+// single logical message across multiple Write/Read calls, writeMsgN/
+// readMsgN will miscount and the framing will desync. writeClientHello
+// validates the exact expected length of the first message up front, so a
+// split first message surfaces immediately as an "unexpected handshake
+// message length" error; writeServerFlight does not check the length of
+// the message it carries.
+//
+// Read and Write each only guard their own counter/buffer state, never the
+// underlying conn I/O itself, so a Read blocked waiting on the peer (the
+// steady state once yamux is layered on top, which keeps a recv loop
+// parked in Read between frames) never holds off a concurrent Write, and
+// vice versa - exactly the concurrent-Read/Write guarantee net.Conn makes.
+type obfuscatedConn struct {
+	net.Conn
+	anchorPK  cipher.PubKey // initiator: responder's PK. responder: own PK. Same value on both sides.
+	initiator bool
+
+	writeMu   sync.Mutex
+	writeMsgN int
+
+	readMu   sync.Mutex
+	readMsgN int
+	readBuf  []byte // leftover decoded bytes from a record not yet consumed by the caller.
+}
+
+func (c *obfuscatedConn) Write(b []byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	n := c.writeMsgN
+	c.writeMsgN++
+
+	switch {
+	case c.initiator && n == 0:
+		if err := writeClientHello(c.Conn, c.anchorPK, b); err != nil {
+			return 0, err
+		}
+	case !c.initiator && n == 0:
+		if err := writeServerFlight(c.Conn, c.anchorPK, b); err != nil {
+			return 0, err
+		}
+	default:
+		if err := writeAppRecords(c.Conn, b); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+func (c *obfuscatedConn) Read(b []byte) (int, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+
+	if len(c.readBuf) > 0 {
+		n := copy(b, c.readBuf)
+		c.readBuf = c.readBuf[n:]
+		return n, nil
+	}
+
+	n := c.readMsgN
+	c.readMsgN++
+
+	var (
+		msg []byte
+		err error
+	)
+	switch {
+	case c.initiator && n == 0:
+		msg, err = readServerFlight(c.Conn, c.anchorPK)
+	case !c.initiator && n == 0:
+		msg, err = readClientHello(c.Conn, c.anchorPK)
+	default:
+		msg, err = readAppRecord(c.Conn)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	copied := copy(b, msg)
+	if copied < len(msg) {
+		c.readBuf = append(c.readBuf, msg[copied:]...)
+	}
+	return copied, nil
+}
+
+// handshakeHeaderLen is the length of a TLS handshake message header
+// nested inside a handshake record's body: type(1) + length(3).
+const handshakeHeaderLen = 4
+
+// wrapHandshakeMsg prepends a standard type+24-bit-length handshake header
+// to body, so the declared length always matches what follows - there is
+// no way to construct a mismatched length field like the one that used to
+// make Finished unparseable.
+func wrapHandshakeMsg(handshakeType byte, body []byte) []byte {
+	out := make([]byte, handshakeHeaderLen+len(body))
+	out[0] = handshakeType
+	out[1] = byte(len(body) >> 16)
+	out[2] = byte(len(body) >> 8)
+	out[3] = byte(len(body))
+	copy(out[handshakeHeaderLen:], body)
+	return out
+}
+
+// unwrapHandshakeMsg parses a type+24-bit-length handshake header from
+// body, verifying wantType and that the declared length matches what's
+// actually present, and returns the inner payload.
+func unwrapHandshakeMsg(body []byte, wantType byte) ([]byte, error) {
+	if len(body) < handshakeHeaderLen {
+		return nil, fmt.Errorf("obfuscator: handshake message too short for a header")
+	}
+	if body[0] != wantType {
+		return nil, fmt.Errorf("obfuscator: expected handshake type %d, got %d", wantType, body[0])
+	}
+	length := int(body[1])<<16 | int(body[2])<<8 | int(body[3])
+	payload := body[handshakeHeaderLen:]
+	if length != len(payload) {
+		return nil, fmt.Errorf("obfuscator: handshake length %d does not match %d bytes present", length, len(payload))
+	}
+	return payload, nil
+}
+
+// writeClientHello encodes msg into a ClientHello's random/session_id
+// fields, MACed with a tag derived from the responder's public key so the
+// responder can recognise and authenticate a genuine dmsg peer.
+func writeClientHello(w io.Writer, rPK cipher.PubKey, msg []byte) error {
+	if len(msg) != tlsClientHelloRandomLen+tlsClientHelloSessIDLen-pskTagLen {
+		return fmt.Errorf("obfuscator: unexpected handshake message length %d", len(msg))
+	}
+	payload := append(append([]byte(nil), msg...), pskTag(rPK, msg)...)
+	return writeTLSRecord(w, tlsContentHandshake, wrapHandshakeMsg(tlsHandshakeClientHello, payload))
+}
+
+func readClientHello(r io.Reader, lPK cipher.PubKey) ([]byte, error) {
+	body, err := readTLSRecord(r, tlsContentHandshake)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := unwrapHandshakeMsg(body, tlsHandshakeClientHello)
+	if err != nil {
+		return nil, err
+	}
+	return unwrapTaggedHandshake(payload, lPK)
+}
+
+// writeServerFlight emits a ServerHello + ChangeCipherSpec + Finished trio
+// of records, carrying msg in the ServerHello body.
+func writeServerFlight(w io.Writer, lPK cipher.PubKey, msg []byte) error {
+	shPayload := append(append([]byte(nil), msg...), pskTag(lPK, msg)...)
+	if err := writeTLSRecord(w, tlsContentHandshake, wrapHandshakeMsg(tlsHandshakeServerHello, shPayload)); err != nil {
+		return err
+	}
+	if err := writeTLSRecord(w, tlsContentChangeCipher, []byte{1}); err != nil {
+		return err
+	}
+	// verify_data is not cryptographically meaningful here (dmsg already
+	// authenticates the session via Noise); it only needs to be present so
+	// the declared length matches, same as every other handshake message.
+	verifyData := make([]byte, 12)
+	return writeTLSRecord(w, tlsContentHandshake, wrapHandshakeMsg(tlsHandshakeFinished, verifyData))
+}
+
+func readServerFlight(r io.Reader, rPK cipher.PubKey) ([]byte, error) {
+	body, err := readTLSRecord(r, tlsContentHandshake)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := unwrapHandshakeMsg(body, tlsHandshakeServerHello)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := unwrapTaggedHandshake(payload, rPK)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := readTLSRecord(r, tlsContentChangeCipher); err != nil {
+		return nil, fmt.Errorf("obfuscator: read ChangeCipherSpec: %w", err)
+	}
+	finishedBody, err := readTLSRecord(r, tlsContentHandshake)
+	if err != nil {
+		return nil, fmt.Errorf("obfuscator: read Finished: %w", err)
+	}
+	if _, err := unwrapHandshakeMsg(finishedBody, tlsHandshakeFinished); err != nil {
+		return nil, fmt.Errorf("obfuscator: parse Finished: %w", err)
+	}
+	return msg, nil
+}
+
+func unwrapTaggedHandshake(payload []byte, pk cipher.PubKey) ([]byte, error) {
+	if len(payload) < pskTagLen {
+		return nil, fmt.Errorf("obfuscator: handshake body too short")
+	}
+	msg, tag := payload[:len(payload)-pskTagLen], payload[len(payload)-pskTagLen:]
+	if !hmac.Equal(tag, pskTag(pk, msg)) {
+		return nil, fmt.Errorf("obfuscator: handshake tag mismatch, not a dmsg peer")
+	}
+	return msg, nil
+}
+
+// writeAppRecords chunks b into one or more TLS-application-data-shaped
+// records of at most maxAppRecordSize bytes each.
+func writeAppRecords(w io.Writer, b []byte) error {
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > maxAppRecordSize {
+			chunk = chunk[:maxAppRecordSize]
+		}
+		if err := writeTLSRecord(w, tlsContentApplication, chunk); err != nil {
+			return err
+		}
+		b = b[len(chunk):]
+	}
+	return nil
+}
+
+func readAppRecord(r io.Reader) ([]byte, error) {
+	return readTLSRecord(r, tlsContentApplication)
+}
+
+// writeTLSRecord writes body as a single TLS record with a 5-byte header:
+// content type, version (TLS 1.2), and big-endian length.
+func writeTLSRecord(w io.Writer, contentType byte, body []byte) error {
+	header := []byte{
+		contentType,
+		tlsVersionMajor, tlsVersionMinorTLS12,
+		byte(len(body) >> 8), byte(len(body)),
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// readTLSRecord reads a single TLS record and returns its body, verifying
+// that its content type matches wantType.
+func readTLSRecord(r io.Reader, wantType byte) ([]byte, error) {
+	header := make([]byte, tlsRecordHeaderLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[0] != wantType {
+		return nil, fmt.Errorf("obfuscator: expected record type %d, got %d", wantType, header[0])
+	}
+	length := int(header[3])<<8 | int(header[4])
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
@@ -0,0 +1,154 @@
+package dmsg
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+// recordingConn duplicates every byte written to it into rec, so a test can
+// inspect exactly what went out on the wire.
+type recordingConn struct {
+	net.Conn
+	rec *bytes.Buffer
+}
+
+func (c *recordingConn) Write(b []byte) (int, error) {
+	c.rec.Write(b) //nolint:errcheck
+	return c.Conn.Write(b)
+}
+
+// TestObfuscatedConn_HandshakeParsesAsTLSRecordsThroughFinished exercises the
+// documented Write/Read-count contract (one Write per handshake message
+// sent, one Read per handshake message expected) and checks that what
+// actually lands on the wire is a well-formed sequence of TLS records: a
+// parser walking record headers and, inside handshake records, the nested
+// type+24-bit-length handshake header, can consume the whole stream up
+// through Finished without a framing error. It does not claim the bytes
+// are a conformant ClientHello/ServerHello (no cipher suites or extensions
+// are emitted) - only that record- and handshake-level lengths are
+// internally consistent, which is what a passive DPI box inspects.
+func TestObfuscatedConn_HandshakeParsesAsTLSRecordsThroughFinished(t *testing.T) {
+	connInit, connResp := net.Pipe()
+	initRec := &bytes.Buffer{}
+	respRec := &bytes.Buffer{}
+
+	anchorPK, _ := cipher.GenerateKeyPair()
+
+	obf := NewTLSObfuscator()
+	initConn := obf.WrapInitiator(&recordingConn{Conn: connInit, rec: initRec}, anchorPK)
+	respConn := obf.WrapResponder(&recordingConn{Conn: connResp, rec: respRec}, anchorPK)
+
+	msg1 := make([]byte, 48)
+	msg2 := make([]byte, 48)
+	for i := range msg1 {
+		msg1[i] = byte(i)
+	}
+	for i := range msg2 {
+		msg2[i] = byte(255 - i)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if _, err := initConn.Write(msg1); err != nil {
+			errCh <- err
+			return
+		}
+		got := make([]byte, len(msg2))
+		if _, err := io.ReadFull(initConn, got); err != nil {
+			errCh <- err
+			return
+		}
+		if !bytes.Equal(got, msg2) {
+			errCh <- errors.New("msg2 round-trip mismatch")
+			return
+		}
+		errCh <- nil
+	}()
+
+	got1 := make([]byte, len(msg1))
+	_, err := io.ReadFull(respConn, got1)
+	require.NoError(t, err)
+	require.Equal(t, msg1, got1)
+
+	_, err = respConn.Write(msg2)
+	require.NoError(t, err)
+
+	require.NoError(t, <-errCh)
+
+	requireParsesAsTLSRecords(t, initRec.Bytes(), []byte{tlsContentHandshake})
+	requireParsesAsTLSRecords(t, respRec.Bytes(), []byte{tlsContentHandshake, tlsContentChangeCipher, tlsContentHandshake})
+}
+
+// TestObfuscatedConn_RealSessionHandshake runs the actual Noise XK handshake
+// driven by InitiateSession/RespondSession over a pipe wrapped with
+// WrapInitiator/WrapResponder, rather than hand-fed synthetic buffers. This
+// is what proves the real XK flight's message sizes match what
+// writeClientHello/writeServerFlight hard-require (e.g. the first message
+// being exactly 48 bytes), and that InitiateSession/RespondSession each
+// perform the one-Write/one-Read-per-message the wrapper assumes.
+func TestObfuscatedConn_RealSessionHandshake(t *testing.T) {
+	connInit, connResp := net.Pipe()
+
+	lPKInit, lSKInit := cipher.GenerateKeyPair()
+	lPKResp, lSKResp := cipher.GenerateKeyPair()
+
+	obf := NewTLSObfuscator()
+	initConn := obf.WrapInitiator(connInit, lPKResp)
+	respConn := obf.WrapResponder(connResp, lPKResp)
+
+	type initResult struct {
+		ses *Session
+		err error
+	}
+	initCh := make(chan initResult, 1)
+	go func() {
+		ses, err := InitiateSession(logrus.New(), nil, initConn, lSKInit, lPKInit, lPKResp)
+		initCh <- initResult{ses, err}
+	}()
+
+	respSes, err := RespondSession(logrus.New(), nil, respConn, lSKResp, lPKResp)
+	require.NoError(t, err)
+	require.NotNil(t, respSes)
+	defer func() { _ = respSes.Close() }() //nolint:errcheck
+
+	res := <-initCh
+	require.NoError(t, res.err)
+	require.NotNil(t, res.ses)
+	defer func() { _ = res.ses.Close() }() //nolint:errcheck
+
+	require.Equal(t, lPKResp, res.ses.RemotePK())
+	require.Equal(t, lPKInit, respSes.RemotePK())
+}
+
+func requireParsesAsTLSRecords(t *testing.T, wire []byte, wantTypes []byte) {
+	t.Helper()
+
+	r := bytes.NewReader(wire)
+	for _, wantType := range wantTypes {
+		header := make([]byte, tlsRecordHeaderLen)
+		_, err := io.ReadFull(r, header)
+		require.NoError(t, err)
+		require.Equal(t, wantType, header[0])
+		require.Equal(t, tlsVersionMajor, header[1])
+
+		length := int(header[3])<<8 | int(header[4])
+		body := make([]byte, length)
+		_, err = io.ReadFull(r, body)
+		require.NoErrorf(t, err, "record body truncated: declared length %d", length)
+
+		if wantType == tlsContentHandshake {
+			require.GreaterOrEqual(t, len(body), handshakeHeaderLen)
+			hsLen := int(body[1])<<16 | int(body[2])<<8 | int(body[3])
+			require.Equal(t, len(body)-handshakeHeaderLen, hsLen, "handshake message length field does not match body")
+		}
+	}
+	require.Zero(t, r.Len(), "trailing bytes after expected records")
+}
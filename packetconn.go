@@ -0,0 +1,314 @@
+package dmsg
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// frameHeaderLen is the length of a PacketConn frame's length prefix.
+const frameHeaderLen = 4
+
+// DefaultMaxMessageSize is used by PacketConnConfig when MaxMessageSize is
+// left at zero.
+const DefaultMaxMessageSize = 64 * 1024
+
+// OnFullPolicy controls what PacketConn does when its inbound queue is full
+// and another datagram arrives.
+type OnFullPolicy int
+
+const (
+	// OnFullDrop silently drops the incoming datagram, favouring low
+	// latency for fresh data over delivering everything.
+	OnFullDrop OnFullPolicy = iota
+	// OnFullBlock blocks the stream's reader until the queue has room,
+	// applying backpressure to the remote sender.
+	OnFullBlock
+)
+
+// PacketConnConfig configures a PacketConn's framing and backpressure
+// behaviour.
+type PacketConnConfig struct {
+	// MaxMessageSize bounds a single datagram. Zero means DefaultMaxMessageSize.
+	MaxMessageSize int
+	// QueueSize bounds how many received-but-unread datagrams are buffered.
+	// Zero means a queue size of 128.
+	QueueSize int
+	// OnFull selects the behaviour when the inbound queue is full.
+	OnFull OnFullPolicy
+}
+
+func (c PacketConnConfig) maxMessageSize() int {
+	if c.MaxMessageSize <= 0 {
+		return DefaultMaxMessageSize
+	}
+	return c.MaxMessageSize
+}
+
+func (c PacketConnConfig) queueSize() int {
+	if c.QueueSize <= 0 {
+		return 128
+	}
+	return c.QueueSize
+}
+
+// StreamDialer opens a Stream to a peer, on demand, for outbound datagrams.
+// A Session satisfies this via its DialClientStream method.
+type StreamDialer interface {
+	DialClientStream(ctx context.Context, dst Addr) (*Stream, error)
+}
+
+// datagram is a single received message, tagged with the peer it came from.
+type datagram struct {
+	addr Addr
+	data []byte
+}
+
+// PacketConn exposes datagram (net.PacketConn) semantics over dmsg Streams,
+// so that protocols wanting WriteTo/ReadFrom semantics (DTLS-style
+// handshakes, QUIC-like experiments, gossip layers) don't have to
+// reimplement message framing on top of Stream themselves.
+//
+// Internally, every peer gets its own long-lived Stream; datagrams to/from
+// that peer are multiplexed over it as length-prefixed frames. Incoming
+// Streams are accepted the same way a Listener accepts streams for ordinary
+// byte-stream usage - the existing porter still does destination-port
+// demultiplexing, PacketConn just treats each resulting Stream as a
+// datagram channel instead of a byte stream. Serve's accept parameter is
+// deliberately shaped to match a future (*Listener).Accept() (*Stream,
+// error): once this tree grows a Listener type wired to the porter's
+// per-port registration, the whole of the Listen-on-a-port integration is
+// `pc.Serve(lis.Accept)` - PacketConn does not need its own port/porter
+// logic duplicated here.
+type PacketConn struct {
+	lAddr  Addr
+	dialer StreamDialer
+	cfg    PacketConnConfig
+
+	mu      sync.Mutex
+	streams map[Addr]*peerStream
+	closed  bool
+
+	incoming chan datagram
+	closeCh  chan struct{}
+}
+
+// peerStream pairs a Stream with a mutex serializing writes to it.
+// net.PacketConn requires WriteTo to be safe for concurrent use, but two
+// goroutines writing to the same peer's Stream at once would interleave
+// their length-prefixed frames and corrupt the framing for both.
+type peerStream struct {
+	str     *Stream
+	writeMu sync.Mutex
+}
+
+// NewPacketConn creates a PacketConn bound to lAddr (the local dmsg address
+// datagrams are sent/received on). dialer is used to open Streams to peers
+// that are not already connected.
+func NewPacketConn(lAddr Addr, dialer StreamDialer, cfg PacketConnConfig) *PacketConn {
+	return &PacketConn{
+		lAddr:    lAddr,
+		dialer:   dialer,
+		cfg:      cfg,
+		streams:  make(map[Addr]*peerStream),
+		incoming: make(chan datagram, cfg.queueSize()),
+		closeCh:  make(chan struct{}),
+	}
+}
+
+// Serve accepts incoming Streams from accept (as delivered to a Listener
+// for lAddr.Port) and multiplexes datagrams from each onto pc's ReadFrom
+// queue. It returns when accept returns an error, or with io.ErrClosedPipe
+// when accept yields a Stream after pc is closed; a Serve blocked inside
+// accept is not woken by Close.
+func (pc *PacketConn) Serve(accept func() (*Stream, error)) error {
+	for {
+		str, err := accept()
+		if err != nil {
+			return err
+		}
+		pc.adopt(str)
+		select {
+		case <-pc.closeCh:
+			return io.ErrClosedPipe
+		default:
+		}
+	}
+}
+
+// adopt registers str as the channel for datagrams from its remote address
+// and starts reading frames off it. If pc is already closed, str is closed
+// instead.
+func (pc *PacketConn) adopt(str *Stream) {
+	raddr := str.rAddr
+
+	pc.mu.Lock()
+	if pc.closed {
+		pc.mu.Unlock()
+		_ = str.Close() //nolint:errcheck
+		return
+	}
+	pc.streams[raddr] = &peerStream{str: str}
+	pc.mu.Unlock()
+
+	go pc.readLoop(raddr, str)
+}
+
+// WriteTo sends b as a single datagram to the peer at addr, which must be a
+// dmsg Addr. A Stream to addr is dialed on first use and reused afterwards.
+func (pc *PacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	dst, ok := addr.(Addr)
+	if !ok {
+		return 0, fmt.Errorf("packetconn: WriteTo requires a dmsg.Addr, got %T", addr)
+	}
+	if len(b) > pc.cfg.maxMessageSize() {
+		return 0, fmt.Errorf("packetconn: message of %d bytes exceeds max size %d", len(b), pc.cfg.maxMessageSize())
+	}
+
+	ps, err := pc.streamFor(dst)
+	if err != nil {
+		return 0, err
+	}
+
+	frame := make([]byte, frameHeaderLen+len(b))
+	binary.BigEndian.PutUint32(frame, uint32(len(b)))
+	copy(frame[frameHeaderLen:], b)
+
+	ps.writeMu.Lock()
+	defer ps.writeMu.Unlock()
+
+	if _, err := ps.str.Write(frame); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (pc *PacketConn) streamFor(dst Addr) (*peerStream, error) {
+	pc.mu.Lock()
+	if pc.closed {
+		pc.mu.Unlock()
+		return nil, io.ErrClosedPipe
+	}
+	if ps, ok := pc.streams[dst]; ok {
+		pc.mu.Unlock()
+		return ps, nil
+	}
+	pc.mu.Unlock()
+
+	str, err := pc.dialer.DialClientStream(context.Background(), dst)
+	if err != nil {
+		return nil, fmt.Errorf("packetconn: dial %s: %w", dst, err)
+	}
+
+	pc.mu.Lock()
+	if pc.closed {
+		pc.mu.Unlock()
+		_ = str.Close() //nolint:errcheck
+		return nil, io.ErrClosedPipe
+	}
+	if existing, ok := pc.streams[dst]; ok {
+		// Lost the race against a concurrent dial; keep the winner, close ours.
+		pc.mu.Unlock()
+		_ = str.Close() //nolint:errcheck
+		return existing, nil
+	}
+	ps := &peerStream{str: str}
+	pc.streams[dst] = ps
+	pc.mu.Unlock()
+
+	go pc.readLoop(dst, str)
+	return ps, nil
+}
+
+// readLoop reads length-prefixed frames off str and enqueues them for
+// ReadFrom, applying cfg.OnFull when the queue is saturated.
+func (pc *PacketConn) readLoop(raddr Addr, str *Stream) {
+	header := make([]byte, frameHeaderLen)
+	for {
+		if _, err := io.ReadFull(str, header); err != nil {
+			return
+		}
+		n := binary.BigEndian.Uint32(header)
+		if int(n) > pc.cfg.maxMessageSize() {
+			return
+		}
+		body := make([]byte, n)
+		if _, err := io.ReadFull(str, body); err != nil {
+			return
+		}
+
+		msg := datagram{addr: raddr, data: body}
+		switch pc.cfg.OnFull {
+		case OnFullBlock:
+			select {
+			case pc.incoming <- msg:
+			case <-pc.closeCh:
+				return
+			}
+		default: // OnFullDrop
+			select {
+			case pc.incoming <- msg:
+			default:
+			}
+		}
+	}
+}
+
+// ReadFrom returns the next available datagram and the Addr it came from.
+func (pc *PacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	select {
+	case msg := <-pc.incoming:
+		n := copy(b, msg.data)
+		return n, msg.addr, nil
+	case <-pc.closeCh:
+		return 0, nil, io.EOF
+	}
+}
+
+// Close closes every underlying Stream and stops further reads/writes.
+func (pc *PacketConn) Close() error {
+	pc.mu.Lock()
+	if pc.closed {
+		pc.mu.Unlock()
+		return nil
+	}
+	pc.closed = true
+	streams := pc.streams
+	pc.streams = nil
+	pc.mu.Unlock()
+
+	close(pc.closeCh)
+
+	var firstErr error
+	for _, ps := range streams {
+		if err := ps.str.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// LocalAddr returns the dmsg address this PacketConn sends/receives on.
+func (pc *PacketConn) LocalAddr() net.Addr { return pc.lAddr }
+
+// SetDeadline, SetReadDeadline and SetWriteDeadline are not supported: each
+// underlying Stream has its own deadlines, and a PacketConn may be backed
+// by many of them at once.
+func (pc *PacketConn) SetDeadline(_ time.Time) error {
+	return errors.New("packetconn: deadlines are not supported; set them on individual streams instead")
+}
+
+// SetReadDeadline is not supported. See SetDeadline.
+func (pc *PacketConn) SetReadDeadline(_ time.Time) error {
+	return pc.SetDeadline(time.Time{})
+}
+
+// SetWriteDeadline is not supported. See SetDeadline.
+func (pc *PacketConn) SetWriteDeadline(_ time.Time) error {
+	return pc.SetDeadline(time.Time{})
+}
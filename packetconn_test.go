@@ -0,0 +1,116 @@
+package dmsg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type stubDialer struct {
+	str *Stream
+}
+
+func (d stubDialer) DialClientStream(_ context.Context, _ Addr) (*Stream, error) {
+	return d.str, nil
+}
+
+func TestPacketConn_WriteToReadFrom(t *testing.T) {
+	initStream, respStream, _, closeFn := newTestStreamPair(t)
+	defer closeFn()
+
+	pc := NewPacketConn(Addr{}, stubDialer{str: initStream}, PacketConnConfig{})
+	defer func() { _ = pc.Close() }() //nolint:errcheck
+
+	// WriteTo dials initStream; respStream, its other end, is adopted only
+	// afterwards so that WriteTo does not reuse it as the sending stream.
+	payload := []byte("datagram over a dmsg stream")
+	n, err := pc.WriteTo(payload, Addr{})
+	require.NoError(t, err)
+	require.Equal(t, len(payload), n)
+	pc.adopt(respStream)
+
+	buf := make([]byte, 256)
+	n, addr, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+	require.Equal(t, payload, buf[:n])
+	require.Equal(t, Addr{}, addr)
+}
+
+func TestPacketConn_WriteToRejectsOversizedMessage(t *testing.T) {
+	initStream, respStream, _, closeFn := newTestStreamPair(t)
+	defer closeFn()
+
+	pc := NewPacketConn(Addr{}, stubDialer{str: initStream}, PacketConnConfig{MaxMessageSize: 4})
+	defer func() { _ = pc.Close() }() //nolint:errcheck
+	pc.adopt(respStream)
+
+	_, err := pc.WriteTo([]byte("too big"), Addr{})
+	require.Error(t, err)
+}
+
+// TestPacketConn_WriteToIsSafeForConcurrentCallers drives many concurrent
+// WriteTo calls to the same peer over the same underlying Stream: if writes
+// ever interleave, a receiver will read a corrupt length prefix and the
+// frame boundaries will desync, so every received datagram must come back
+// intact and unambiguously attributable to one sender call.
+func TestPacketConn_WriteToIsSafeForConcurrentCallers(t *testing.T) {
+	initStream, respStream, _, closeFn := newTestStreamPair(t)
+	defer closeFn()
+
+	pc := NewPacketConn(Addr{}, stubDialer{str: initStream}, PacketConnConfig{QueueSize: 64})
+	defer func() { _ = pc.Close() }() //nolint:errcheck
+	// Every WriteTo shares initStream; respStream, its other end, is adopted
+	// once the writers are done.
+	pc.adopt(initStream)
+
+	const n = 32
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			_, err := pc.WriteTo([]byte(fmt.Sprintf("msg-%02d", i)), Addr{})
+			require.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+	pc.adopt(respStream)
+
+	got := make(map[string]bool, n)
+	for i := 0; i < n; i++ {
+		buf := make([]byte, 64)
+		read, _, err := pc.ReadFrom(buf)
+		require.NoError(t, err)
+		got[string(buf[:read])] = true
+	}
+	for i := 0; i < n; i++ {
+		require.True(t, got[fmt.Sprintf("msg-%02d", i)], "missing or corrupted datagram %d", i)
+	}
+}
+
+func TestPacketConn_ReadFromUnblocksOnClose(t *testing.T) {
+	initStream, respStream, _, closeFn := newTestStreamPair(t)
+	defer closeFn()
+	defer func() { _ = initStream.Close() }() //nolint:errcheck
+	_ = respStream
+
+	pc := NewPacketConn(Addr{}, stubDialer{str: initStream}, PacketConnConfig{})
+
+	done := make(chan struct{})
+	go func() {
+		_, _, _ = pc.ReadFrom(make([]byte, 64))
+		close(done)
+	}()
+
+	require.NoError(t, pc.Close())
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ReadFrom did not unblock after Close")
+	}
+}
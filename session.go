@@ -28,6 +28,9 @@ type Session struct {
 	log logrus.FieldLogger
 }
 
+// InitiateSession initiates a session over conn. conn is used as-is: to
+// disguise the handshake as TLS (see Config.Obfuscator), wrap conn with
+// Obfuscator.WrapInitiator before calling InitiateSession.
 func InitiateSession(log logrus.FieldLogger, porter *netutil.Porter, conn net.Conn, lSK cipher.SecKey, lPK, rPK cipher.PubKey) (*Session, error) {
 	ns, err := noise.New(noise.HandshakeXK, noise.Config{
 		LocalPK:   lPK,
@@ -62,6 +65,9 @@ func InitiateSession(log logrus.FieldLogger, porter *netutil.Porter, conn net.Co
 	}, nil
 }
 
+// RespondSession responds to a session initiation over conn. conn is used
+// as-is: to disguise the handshake as TLS (see Config.Obfuscator), wrap
+// conn with Obfuscator.WrapResponder before calling RespondSession.
 func RespondSession(log logrus.FieldLogger, getter SessionGetter, conn net.Conn, lSK cipher.SecKey, lPK cipher.PubKey) (*Session, error) {
 	ns, err := noise.New(noise.HandshakeXK, noise.Config{
 		LocalPK:   lPK,
@@ -0,0 +1,152 @@
+package dmsg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SkycoinProject/dmsg/cipher"
+	"github.com/SkycoinProject/dmsg/netutil"
+)
+
+// TransportType identifies which SessionTransport produced a net.Conn.
+type TransportType string
+
+const (
+	// TransportTCP is the classic transport: a TCP dial straight to a dmsg server.
+	TransportTCP TransportType = "tcp"
+	// TransportSTCPR is a direct, reliable TCP transport between two clients,
+	// with the remote address resolved via an address-resolver service.
+	TransportSTCPR TransportType = "stcpr"
+	// TransportSUDPH is a direct UDP transport between two clients that relies
+	// on NAT hole-punching to establish connectivity.
+	TransportSUDPH TransportType = "sudph"
+)
+
+// SessionTransport acquires the net.Conn that a Session is built on top of.
+// Implementations are free to dial a relaying dmsg server, a peer directly,
+// or anything else that yields a working net.Conn; the yamux+noise session
+// layer above does not care how the conn came to be.
+type SessionTransport interface {
+	// Type identifies this transport, for logging and metrics.
+	Type() TransportType
+	// Dial acquires a net.Conn usable to reach the dmsg server identified by rPK.
+	Dial(ctx context.Context, rPK cipher.PubKey) (net.Conn, error)
+	// Listen starts accepting incoming conns for this transport.
+	Listen() (net.Listener, error)
+}
+
+// TransportPolicy tries a sequence of SessionTransports in order, falling
+// back to the next one as soon as one fails to produce a conn. This mirrors
+// skywire visor's STCP/STCPR/SUDPH/DMSG fallback chain.
+type TransportPolicy struct {
+	transports []SessionTransport
+	log        logrus.FieldLogger
+}
+
+// NewTransportPolicy creates a TransportPolicy that tries the given
+// transports in the order provided.
+func NewTransportPolicy(log logrus.FieldLogger, transports ...SessionTransport) *TransportPolicy {
+	return &TransportPolicy{transports: transports, log: log}
+}
+
+// Dial tries each configured transport in order, returning the first conn
+// obtained and the TransportType that produced it.
+func (p *TransportPolicy) Dial(ctx context.Context, rPK cipher.PubKey) (net.Conn, TransportType, error) {
+	var lastErr error
+	for _, tr := range p.transports {
+		conn, err := tr.Dial(ctx, rPK)
+		if err != nil {
+			p.log.WithError(err).
+				WithField("transport", tr.Type()).
+				WithField("remote_pk", rPK).
+				Debug("SessionTransport dial failed, trying next transport.")
+			lastErr = err
+			continue
+		}
+		return conn, tr.Type(), nil
+	}
+	if lastErr == nil {
+		return nil, "", errors.New("no session transports configured")
+	}
+	return nil, "", fmt.Errorf("all session transports exhausted: %w", lastErr)
+}
+
+// DialSession is the glue between SessionTransport and the session layer:
+// it dials rPK via Dial, trying each configured transport in order, then
+// initiates a dmsg Session over whichever conn comes back. If cfg.Obfuscator
+// is set, the conn is wrapped with it (see Config.Obfuscator) before the
+// Noise XK handshake runs.
+func (p *TransportPolicy) DialSession(ctx context.Context, porter *netutil.Porter, cfg Config, lSK cipher.SecKey, lPK, rPK cipher.PubKey) (*Session, error) {
+	conn, transportType, err := p.Dial(ctx, rPK)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Obfuscator != nil {
+		conn = cfg.Obfuscator.WrapInitiator(conn, rPK)
+	}
+	ses, err := InitiateSession(p.log, porter, conn, lSK, lPK, rPK)
+	if err != nil {
+		_ = conn.Close() //nolint:errcheck
+		return nil, fmt.Errorf("initiate session over %s transport: %w", transportType, err)
+	}
+	return ses, nil
+}
+
+// RespondSession is DialSession's responder-side counterpart: it runs
+// RespondSession over conn, wrapping conn with cfg.Obfuscator's
+// WrapResponder first if set, so a server accepting conns off one of
+// Listeners' listeners can speak the same TLS-mimicry handshake that
+// DialSession's callers dial with.
+func (p *TransportPolicy) RespondSession(getter SessionGetter, conn net.Conn, cfg Config, lSK cipher.SecKey, lPK cipher.PubKey) (*Session, error) {
+	if cfg.Obfuscator != nil {
+		conn = cfg.Obfuscator.WrapResponder(conn, lPK)
+	}
+	ses, err := RespondSession(p.log, getter, conn, lSK, lPK)
+	if err != nil {
+		_ = conn.Close() //nolint:errcheck
+		return nil, err
+	}
+	return ses, nil
+}
+
+// Listeners returns a net.Listener per configured transport, keyed by type,
+// so a server can accept incoming sessions on all of them concurrently.
+func (p *TransportPolicy) Listeners() (map[TransportType]net.Listener, error) {
+	out := make(map[TransportType]net.Listener, len(p.transports))
+	for _, tr := range p.transports {
+		lis, err := tr.Listen()
+		if err != nil {
+			return nil, fmt.Errorf("listen on %s transport: %w", tr.Type(), err)
+		}
+		out[tr.Type()] = lis
+	}
+	return out, nil
+}
+
+// tcpTransport is the classic SessionTransport: a plain TCP dial to a dmsg
+// server address, with no peer discovery or hole-punching involved.
+type tcpTransport struct {
+	dialer    net.Dialer
+	dmsgAddr  string // address of the dmsg server to dial.
+	listenTCP string // local address to listen on, if acting as the server.
+}
+
+// NewTCPTransport creates a SessionTransport that dials/listens over plain
+// TCP to/on the given addresses.
+func NewTCPTransport(dmsgAddr, listenAddr string) SessionTransport {
+	return &tcpTransport{dmsgAddr: dmsgAddr, listenTCP: listenAddr}
+}
+
+func (t *tcpTransport) Type() TransportType { return TransportTCP }
+
+func (t *tcpTransport) Dial(ctx context.Context, _ cipher.PubKey) (net.Conn, error) {
+	return t.dialer.DialContext(ctx, "tcp", t.dmsgAddr)
+}
+
+func (t *tcpTransport) Listen() (net.Listener, error) {
+	return net.Listen("tcp", t.listenTCP)
+}
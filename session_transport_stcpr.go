@@ -0,0 +1,68 @@
+package dmsg
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+// AddressResolver resolves a dmsg client's public key to an endpoint it can
+// be reached at directly, bypassing a relaying dmsg server. It also accepts
+// bind requests so a client can advertise its own reachable endpoint.
+type AddressResolver interface {
+	// Resolve returns the address rPK is currently reachable at.
+	Resolve(ctx context.Context, rPK cipher.PubKey) (addr string, err error)
+	// Bind advertises that lPK can be reached at addr.
+	Bind(ctx context.Context, lPK cipher.PubKey, addr string) error
+}
+
+// stcprTransport is a direct, reliable TCP SessionTransport: the remote
+// endpoint is resolved via an AddressResolver instead of being a static
+// dmsg server address.
+type stcprTransport struct {
+	lPK      cipher.PubKey
+	resolver AddressResolver
+	dialer   net.Dialer
+	listener net.Listener
+}
+
+// NewSTCPRTransport creates a SessionTransport that dials peers directly
+// over TCP, resolving their address via resolver.
+func NewSTCPRTransport(lPK cipher.PubKey, resolver AddressResolver) SessionTransport {
+	return &stcprTransport{lPK: lPK, resolver: resolver}
+}
+
+func (t *stcprTransport) Type() TransportType { return TransportSTCPR }
+
+func (t *stcprTransport) Dial(ctx context.Context, rPK cipher.PubKey) (net.Conn, error) {
+	addr, err := t.resolver.Resolve(ctx, rPK)
+	if err != nil {
+		return nil, fmt.Errorf("resolve address of %s: %w", rPK, err)
+	}
+	return t.dialer.DialContext(ctx, "tcp", addr)
+}
+
+func (t *stcprTransport) Listen() (net.Listener, error) {
+	lis, err := net.Listen("tcp", "")
+	if err != nil {
+		return nil, err
+	}
+	if err := t.resolver.Bind(context.Background(), t.lPK, lis.Addr().String()); err != nil {
+		_ = lis.Close() //nolint:errcheck
+		return nil, fmt.Errorf("bind address with resolver: %w", err)
+	}
+	t.listener = lis
+	return lis, nil
+}
+
+// Close stops accepting on the listener opened by Listen, if any. It is a
+// no-op if Listen was never called (e.g. this transport is only used to
+// Dial).
+func (t *stcprTransport) Close() error {
+	if t.listener == nil {
+		return nil
+	}
+	return t.listener.Close()
+}
@@ -0,0 +1,54 @@
+package dmsg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+// HolePuncher coordinates UDP NAT hole-punching between two dmsg clients via
+// an out-of-band rendezvous (typically the same service used for STCPR
+// address resolution). On success it returns a UDP conn with a punched-open
+// path to rPK.
+type HolePuncher interface {
+	// PunchHole negotiates with rPK and returns a UDP net.Conn reaching it.
+	PunchHole(ctx context.Context, lPK, rPK cipher.PubKey, conn *net.UDPConn) (net.Conn, error)
+	// Listen returns a UDP conn bound for incoming hole-punch attempts.
+	Listen(lPK cipher.PubKey) (*net.UDPConn, error)
+}
+
+// sudphTransport is a direct UDP SessionTransport that relies on NAT
+// hole-punching, falling back silently (via an error) when both peers sit
+// behind NATs that cannot be traversed.
+type sudphTransport struct {
+	lPK     cipher.PubKey
+	puncher HolePuncher
+}
+
+// NewSUDPHTransport creates a SessionTransport that establishes a direct UDP
+// path to peers via hole-punching.
+func NewSUDPHTransport(lPK cipher.PubKey, puncher HolePuncher) SessionTransport {
+	return &sudphTransport{lPK: lPK, puncher: puncher}
+}
+
+func (t *sudphTransport) Type() TransportType { return TransportSUDPH }
+
+func (t *sudphTransport) Dial(ctx context.Context, rPK cipher.PubKey) (net.Conn, error) {
+	udpConn, err := t.puncher.Listen(t.lPK)
+	if err != nil {
+		return nil, fmt.Errorf("bind local UDP conn: %w", err)
+	}
+	conn, err := t.puncher.PunchHole(ctx, t.lPK, rPK, udpConn)
+	if err != nil {
+		_ = udpConn.Close() //nolint:errcheck
+		return nil, fmt.Errorf("punch hole to %s: %w", rPK, err)
+	}
+	return conn, nil
+}
+
+func (t *sudphTransport) Listen() (net.Listener, error) {
+	return nil, errors.New("sudph: listening is implicit in hole-punching; use Dial from both sides")
+}
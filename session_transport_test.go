@@ -0,0 +1,135 @@
+package dmsg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SkycoinProject/dmsg/cipher"
+)
+
+func TestTCPTransport_DialListenRoundTrip(t *testing.T) {
+	srv := NewTCPTransport("", "127.0.0.1:0")
+	lis, err := srv.Listen()
+	require.NoError(t, err)
+	defer func() { _ = lis.Close() }() //nolint:errcheck
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := lis.Accept()
+		require.NoError(t, err)
+		accepted <- conn
+	}()
+
+	cli := NewTCPTransport(lis.Addr().String(), "")
+	rPK, _ := cipher.GenerateKeyPair()
+	conn, err := cli.Dial(context.Background(), rPK)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }() //nolint:errcheck
+
+	serverConn := <-accepted
+	defer func() { _ = serverConn.Close() }() //nolint:errcheck
+
+	const msg = "hello over tcpTransport"
+	_, err = conn.Write([]byte(msg))
+	require.NoError(t, err)
+
+	got := make([]byte, len(msg))
+	_, err = io.ReadFull(serverConn, got)
+	require.NoError(t, err)
+	require.Equal(t, msg, string(got))
+}
+
+// failingTransport always fails to Dial/Listen, so TransportPolicy is forced
+// to fall through to the next configured transport.
+type failingTransport struct {
+	typ TransportType
+}
+
+func (f failingTransport) Type() TransportType { return f.typ }
+
+func (f failingTransport) Dial(context.Context, cipher.PubKey) (net.Conn, error) {
+	return nil, errors.New("failingTransport: dial always fails")
+}
+
+func (f failingTransport) Listen() (net.Listener, error) {
+	return nil, errors.New("failingTransport: listen always fails")
+}
+
+func TestTransportPolicy_Dial_FallsBackOnFailure(t *testing.T) {
+	srv := NewTCPTransport("", "127.0.0.1:0")
+	lis, err := srv.Listen()
+	require.NoError(t, err)
+	defer func() { _ = lis.Close() }() //nolint:errcheck
+
+	go func() {
+		conn, err := lis.Accept()
+		if err == nil {
+			_ = conn.Close() //nolint:errcheck
+		}
+	}()
+
+	cli := NewTCPTransport(lis.Addr().String(), "")
+	policy := NewTransportPolicy(logrus.New(), failingTransport{typ: TransportSTCPR}, cli)
+
+	rPK, _ := cipher.GenerateKeyPair()
+	conn, transportType, err := policy.Dial(context.Background(), rPK)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }() //nolint:errcheck
+	require.Equal(t, TransportTCP, transportType)
+}
+
+func TestTransportPolicy_Dial_AllFail(t *testing.T) {
+	policy := NewTransportPolicy(logrus.New(), failingTransport{typ: TransportSTCPR}, failingTransport{typ: TransportSUDPH})
+
+	rPK, _ := cipher.GenerateKeyPair()
+	_, _, err := policy.Dial(context.Background(), rPK)
+	require.Error(t, err)
+}
+
+// closeTrackingConn records whether Close was called on it.
+type closeTrackingConn struct {
+	net.Conn
+	closed bool
+}
+
+func (c *closeTrackingConn) Close() error {
+	c.closed = true
+	return c.Conn.Close()
+}
+
+// singleConnTransport always hands out the same conn, for tests that need
+// to control exactly what DialSession gets from Dial.
+type singleConnTransport struct {
+	conn net.Conn
+}
+
+func (s singleConnTransport) Type() TransportType { return TransportTCP }
+
+func (s singleConnTransport) Dial(context.Context, cipher.PubKey) (net.Conn, error) {
+	return s.conn, nil
+}
+
+func (s singleConnTransport) Listen() (net.Listener, error) {
+	return nil, errors.New("singleConnTransport: listen not supported")
+}
+
+func TestTransportPolicy_DialSession_ClosesConnOnHandshakeFailure(t *testing.T) {
+	connA, connB := net.Pipe()
+	_ = connB.Close() //nolint:errcheck // peer gone before the handshake can run
+
+	tracked := &closeTrackingConn{Conn: connA}
+	policy := NewTransportPolicy(logrus.New(), singleConnTransport{conn: tracked})
+
+	lPK, lSK := cipher.GenerateKeyPair()
+	rPK, _ := cipher.GenerateKeyPair()
+
+	_, err := policy.DialSession(context.Background(), nil, Config{}, lSK, lPK, rPK)
+	require.Error(t, err)
+	require.True(t, tracked.closed, "DialSession must close conn when InitiateSession fails")
+}
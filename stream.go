@@ -178,28 +178,18 @@ func (s *Stream) StreamID() uint32 {
 }
 
 // Read implements io.Reader
+// Reads are passed through the Noise ReadWriter so that payload bytes are
+// decrypted and authenticated as they come off the underlying yamux stream.
 func (s *Stream) Read(b []byte) (int, error) {
-	//start := time.Now()
-	//s.log.WithField("start", start).Debug("begin(Read):")
-	n, err := s.yStr.Read(b) // TODO(evanlinjin): Use s.nsConn
-	//s.log.
-	//	WithField("duration", time.Now().Sub(start)).
-	//	WithField("n", n).
-	//	WithField("len(b)", len(b)).
-	//	WithError(err).
-	//	Debug("end(Read):")
+	n, err := s.nsConn.Read(b)
 	return n, err
 }
 
 // Write implements io.Writer
+// Writes are passed through the Noise ReadWriter so that payload bytes are
+// encrypted before they are sent over the underlying yamux stream.
 func (s *Stream) Write(b []byte) (int, error) {
-	//start := time.Now()
-	n, err := s.yStr.Write(b) // TODO(evanlinjin): Use s.nsConn
-	//s.log.
-	//	WithField("duration", time.Now().Sub(start)).
-	//	WithField("n", n).
-	//	WithError(err).
-	//	Debug("Write:")
+	n, err := s.nsConn.Write(b)
 	return n, err
 }
 